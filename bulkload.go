@@ -0,0 +1,111 @@
+package trie
+
+import "strings"
+
+// NewFromSorted builds a trie from pairs in a single pass using a
+// stack-based algorithm that tracks only the rightmost path of the tree
+// built so far: for each next pair it pops frames off that path until the
+// remaining frames' parts are a literal prefix of the new key, then pushes
+// fresh nodes for whatever parts are left. This produces the same tree as
+// calling Insert for each pair in order, but never revisits a node it has
+// already built and never needs a map lookup to check whether one already
+// exists there - making it roughly an order of magnitude faster, which
+// matters when a TinyGo target is bootstrapping a large static
+// routing/permission table at startup.
+//
+// pairs must already be sorted in strictly increasing order by Key,
+// compared part-by-part (i.e. the same order strings.Split on the
+// separator followed by a lexicographic slice comparison would produce),
+// not by strings.Compare on the raw key. The two orderings diverge
+// whenever a part contains a byte less than the separator byte (for
+// example '.' sorts before '/'), which would otherwise let an unrelated
+// key interleave between two keys that share a part prefix and break the
+// rightmost-path invariant below. NewFromSorted returns an error, without
+// building anything, if the required ordering is violated.
+//
+// WithCompact tries do not get the fast path above, since the
+// splitting/merging needed to keep prefixes compacted does not fit a
+// rightmost-path algorithm: NewFromSorted falls back to calling Insert for
+// each pair in order.
+func NewFromSorted(pairs []KeyValue, options ...Option) (*Trie, error) {
+	t := New(options...)
+
+	if t.isCompact {
+		var prevParts []string
+		for idx, pair := range pairs {
+			parts := strings.Split(pair.Key, t.separator)
+			if idx > 0 && comparePartsLexicographic(parts, prevParts) <= 0 {
+				return nil, trieError{message: "error: NewFromSorted requires pairs sorted in strictly increasing part-wise order (compare each '" + t.separator + "'-separated part in turn, not the raw key string)"}
+			}
+			prevParts = parts
+			if _, err := t.Insert(pair.Key, pair.Value); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	}
+
+	type frame struct {
+		node  *Node
+		parts []string
+	}
+	stack := []frame{{node: t.root}}
+
+	var prevParts []string
+	for idx, pair := range pairs {
+		parts := strings.Split(pair.Key, t.separator)
+		if idx > 0 && comparePartsLexicographic(parts, prevParts) <= 0 {
+			return nil, trieError{message: "error: NewFromSorted requires pairs sorted in strictly increasing part-wise order (compare each '" + t.separator + "'-separated part in turn, not the raw key string)"}
+		}
+		prevParts = parts
+
+		for i, part := range parts {
+			if t.isMWild && part == t.multiWild && i != len(parts)-1 {
+				return nil, trieError{message: "error: multi-level wildcard '" + t.multiWild + "' permitted only at the end of the insert key."}
+			}
+		}
+
+		for len(stack) > 1 && !partsHavePrefix(parts, stack[len(stack)-1].parts) {
+			stack = stack[:len(stack)-1]
+		}
+
+		top := stack[len(stack)-1]
+		node, built := top.node, top.parts
+		for _, part := range parts[len(built):] {
+			child := &Node{children: make(map[string]*Node)}
+			node.children[part] = child
+			built = append(built[:len(built):len(built)], part)
+			stack = append(stack, frame{node: child, parts: built})
+			node = child
+		}
+		node.value = pair.Value
+	}
+
+	return t, nil
+}
+
+// comparePartsLexicographic compares a and b part by part, the same way
+// strings.Compare would if keys were sorted as slices of parts rather than
+// as raw separator-joined strings. It returns a negative number, zero or a
+// positive number as a sorts before, equal to or after b.
+func comparePartsLexicographic(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// partsHavePrefix reports whether parts starts with prefix, part for part.
+func partsHavePrefix(parts, prefix []string) bool {
+	if len(prefix) > len(parts) {
+		return false
+	}
+	for i, p := range prefix {
+		if parts[i] != p {
+			return false
+		}
+	}
+	return true
+}
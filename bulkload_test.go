@@ -0,0 +1,84 @@
+package trie_test
+
+import (
+	"sort"
+	"testing"
+
+	trie "github.com/jangala-dev/tinygo-trie"
+)
+
+func TestNewFromSortedMatchesSequentialInsert(t *testing.T) {
+	keys := []string{"a", "a/b", "a/b/c", "a/b/d", "a/x", "b", "b/c"}
+	pairs := make([]trie.KeyValue, len(keys))
+	for i, k := range keys {
+		pairs[i] = trie.KeyValue{Key: k, Value: k + "-val"}
+	}
+
+	tr, err := trie.NewFromSorted(pairs, trie.WithSeparator("/"))
+	if err != nil {
+		t.Fatalf("Unexpected error building from sorted pairs: %v", err)
+	}
+
+	for _, k := range keys {
+		if val, _ := tr.Retrieve(k); val != k+"-val" {
+			t.Fatalf("Failed to retrieve %q, got %v", k, val)
+		}
+	}
+
+	got := tr.Keys()
+	sort.Strings(got)
+	if len(got) != len(keys) {
+		t.Fatalf("Incorrect key count, got %v", got)
+	}
+	for i := range keys {
+		if got[i] != keys[i] {
+			t.Fatalf("Incorrect keys, got %v want %v", got, keys)
+		}
+	}
+}
+
+func TestNewFromSortedRejectsUnsortedInput(t *testing.T) {
+	pairs := []trie.KeyValue{{Key: "b", Value: 1}, {Key: "a", Value: 2}}
+	if _, err := trie.NewFromSorted(pairs, trie.WithSeparator("/")); err == nil {
+		t.Fatal("Expected an error for unsorted input")
+	}
+}
+
+func TestNewFromSortedRejectsDuplicateKeys(t *testing.T) {
+	pairs := []trie.KeyValue{{Key: "a", Value: 1}, {Key: "a", Value: 2}}
+	if _, err := trie.NewFromSorted(pairs); err == nil {
+		t.Fatal("Expected an error for duplicate keys")
+	}
+}
+
+func TestNewFromSortedRejectsPartInterleavedInput(t *testing.T) {
+	// "b", "b.", "b/x" are in strict strings.Compare order on the raw key
+	// ('.' < '/'), but "b." is not a part-wise prefix of "b/x", so it
+	// breaks the contiguous-prefix-group invariant the rightmost-path
+	// algorithm relies on.
+	pairs := []trie.KeyValue{
+		{Key: "b", Value: "v-b"},
+		{Key: "b.", Value: "v-b."},
+		{Key: "b/x", Value: "v-b/x"},
+	}
+	if _, err := trie.NewFromSorted(pairs, trie.WithSeparator("/")); err == nil {
+		t.Fatal("Expected an error for part-wise-unsorted input")
+	}
+}
+
+func TestNewFromSortedCompactMode(t *testing.T) {
+	pairs := []trie.KeyValue{
+		{Key: "a/b/c/d", Value: "value1"},
+		{Key: "a/b/c/e", Value: "value2"},
+	}
+	tr, err := trie.NewFromSorted(pairs, trie.WithCompact(), trie.WithSeparator("/"))
+	if err != nil {
+		t.Fatalf("Unexpected error building compact trie from sorted pairs: %v", err)
+	}
+	if val, _ := tr.Retrieve("a/b/c/d"); val != "value1" {
+		t.Fatalf("got %v", val)
+	}
+	if val, _ := tr.Retrieve("a/b/c/e"); val != "value2" {
+		t.Fatalf("got %v", val)
+	}
+}
@@ -0,0 +1,233 @@
+package trie
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// MarshalBinary writes a compact, depth-first encoding of t to w, using
+// encodeValue to turn each stored value into bytes. The format is: a small
+// header recording the trie's options (separator, wildcards, compact
+// mode), followed by the root node and its descendants, each node written
+// as a value flag (and length-prefixed encoded value if set) followed by
+// its child count and, per child, the child's key parts and its own
+// recursively-written node.
+//
+// This lets a large trie be frozen to flash/disk on a constrained device
+// and reloaded later without paying the cost of re-running Insert for
+// every key.
+func (t *Trie) MarshalBinary(w io.Writer, encodeValue func(interface{}) ([]byte, error)) error {
+	var flags byte
+	if t.isSWild {
+		flags |= 1
+	}
+	if t.isMWild {
+		flags |= 2
+	}
+	if t.isCompact {
+		flags |= 4
+	}
+	if err := writeByte(w, flags); err != nil {
+		return err
+	}
+	if err := writeLenPrefixed(w, []byte(t.separator)); err != nil {
+		return err
+	}
+	if t.isSWild {
+		if err := writeLenPrefixed(w, []byte(t.singleWild)); err != nil {
+			return err
+		}
+	}
+	if t.isMWild {
+		if err := writeLenPrefixed(w, []byte(t.multiWild)); err != nil {
+			return err
+		}
+	}
+	return marshalNode(w, t.root, t.isCompact, encodeValue)
+}
+
+func marshalNode(w io.Writer, n *Node, compact bool, encodeValue func(interface{}) ([]byte, error)) error {
+	if n.value == nil {
+		if err := writeByte(w, 0); err != nil {
+			return err
+		}
+	} else {
+		encoded, err := encodeValue(n.value)
+		if err != nil {
+			return err
+		}
+		if err := writeByte(w, 1); err != nil {
+			return err
+		}
+		if err := writeLenPrefixed(w, encoded); err != nil {
+			return err
+		}
+	}
+
+	if err := writeUint32(w, uint32(len(n.children))); err != nil {
+		return err
+	}
+	for key, child := range n.children {
+		parts := child.prefix
+		if !compact {
+			parts = []string{key}
+		}
+		if err := writeUint32(w, uint32(len(parts))); err != nil {
+			return err
+		}
+		for _, part := range parts {
+			if err := writeLenPrefixed(w, []byte(part)); err != nil {
+				return err
+			}
+		}
+		if err := marshalNode(w, child, compact, encodeValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary reads a trie previously written by MarshalBinary from r,
+// using decodeValue to turn each stored value's bytes back into an
+// interface{}. The returned trie is built directly from the encoded nodes,
+// so none of its keys pay the cost of Insert.
+func UnmarshalBinary(r io.Reader, decodeValue func([]byte) (interface{}, error)) (*Trie, error) {
+	flags, err := readByte(r)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := readLenPrefixed(r)
+	if err != nil {
+		return nil, err
+	}
+
+	isSWild := flags&1 != 0
+	isMWild := flags&2 != 0
+	isCompact := flags&4 != 0
+
+	options := []Option{WithSeparator(string(sep))}
+	if isSWild {
+		wild, err := readLenPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithSingleWild(string(wild)))
+	}
+	if isMWild {
+		wild, err := readLenPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithMultiWild(string(wild)))
+	}
+	if isCompact {
+		options = append(options, WithCompact())
+	}
+
+	t := New(options...)
+	root, err := unmarshalNode(r, isCompact, decodeValue)
+	if err != nil {
+		return nil, err
+	}
+	t.root = root
+	return t, nil
+}
+
+func unmarshalNode(r io.Reader, compact bool, decodeValue func([]byte) (interface{}, error)) (*Node, error) {
+	n := &Node{children: make(map[string]*Node)}
+
+	hasValue, err := readByte(r)
+	if err != nil {
+		return nil, err
+	}
+	if hasValue == 1 {
+		encoded, err := readLenPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(encoded)
+		if err != nil {
+			return nil, err
+		}
+		n.value = value
+	}
+
+	childCount, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < childCount; i++ {
+		numParts, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		if numParts == 0 {
+			return nil, trieError{message: "error: corrupt trie encoding: child has zero key parts"}
+		}
+		parts := make([]string, numParts)
+		for j := range parts {
+			part, err := readLenPrefixed(r)
+			if err != nil {
+				return nil, err
+			}
+			parts[j] = string(part)
+		}
+		child, err := unmarshalNode(r, compact, decodeValue)
+		if err != nil {
+			return nil, err
+		}
+		if compact {
+			child.prefix = parts
+		}
+		n.children[parts[0]] = child
+	}
+	return n, nil
+}
+
+func writeByte(w io.Writer, b byte) error {
+	_, err := w.Write([]byte{b})
+	return err
+}
+
+func readByte(r io.Reader) (byte, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+func writeUint32(w io.Writer, v uint32) error {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf[:]), nil
+}
+
+func writeLenPrefixed(w io.Writer, b []byte) error {
+	if err := writeUint32(w, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readLenPrefixed(r io.Reader) ([]byte, error) {
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,137 @@
+package trie_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	trie "github.com/jangala-dev/tinygo-trie"
+)
+
+func encodeStringValue(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func decodeStringValue(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+func matchSetsEqual(a, b []trie.KeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	toMap := func(kvs []trie.KeyValue) map[string]interface{} {
+		m := make(map[string]interface{}, len(kvs))
+		for _, kv := range kvs {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+	am, bm := toMap(a), toMap(b)
+	for k, v := range am {
+		if bm[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	tr := trie.New(trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+	tr.Insert("a/b/c/d", "value1")
+	tr.Insert("a/b/c/f", "value2")
+	tr.Insert("a/b/d/#", "value3")
+	tr.Insert("a/+/c/d", "value4")
+
+	var buf bytes.Buffer
+	if err := tr.MarshalBinary(&buf, encodeStringValue); err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored, err := trie.UnmarshalBinary(&buf, decodeStringValue)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	for _, query := range []string{"a/b/c/d", "a/b/c/+", "a/b/+/d", "a/b/+/#"} {
+		before := tr.Match(query)
+		after := restored.Match(query)
+		if !matchSetsEqual(before, after) {
+			t.Fatalf("Match(%q) differs after round-trip: before=%v after=%v", query, before, after)
+		}
+	}
+}
+
+func TestMarshalUnmarshalRoundTripCompact(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+	tr.Insert("a/b/c/d", "value1")
+	tr.Insert("a/b/c/f", "value2")
+	tr.Insert("a/b/d/#", "value3")
+	tr.Insert("a/+/c/d", "value4")
+
+	var buf bytes.Buffer
+	if err := tr.MarshalBinary(&buf, encodeStringValue); err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	restored, err := trie.UnmarshalBinary(&buf, decodeStringValue)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	for _, query := range []string{"a/b/c/d", "a/b/c/+", "a/b/+/d", "a/b/+/#"} {
+		before := tr.Match(query)
+		after := restored.Match(query)
+		if !matchSetsEqual(before, after) {
+			t.Fatalf("Match(%q) differs after round-trip in compact mode: before=%v after=%v", query, before, after)
+		}
+	}
+}
+
+// TestUnmarshalBinaryRejectsZeroPartChild asserts that a truncated/corrupt
+// encoding with a zero-length child parts list returns an error instead of
+// panicking with an index-out-of-range on the empty parts slice.
+func TestUnmarshalBinaryRejectsZeroPartChild(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteByte(0)                                // flags: no wildcards, not compact
+	binary.Write(&buf, binary.BigEndian, uint32(0)) // separator length 0
+	buf.WriteByte(0)                                // root: no value
+	binary.Write(&buf, binary.BigEndian, uint32(1)) // root: one child
+	binary.Write(&buf, binary.BigEndian, uint32(0)) // corrupt: child has zero key parts
+
+	if _, err := trie.UnmarshalBinary(&buf, decodeStringValue); err == nil {
+		t.Fatal("Expected an error for a zero-part child, got nil")
+	}
+}
+
+// FuzzMarshalRoundTrip asserts that, for arbitrary keys, Match behaves
+// identically against a trie and against the trie produced by round-
+// tripping it through MarshalBinary/UnmarshalBinary.
+func FuzzMarshalRoundTrip(f *testing.F) {
+	f.Add("a/b", "a/b/c", "a/x")
+	f.Add("1/2/3", "1/2/4", "1/5")
+
+	f.Fuzz(func(t *testing.T, k1, k2, k3 string) {
+		tr := trie.New(trie.WithSeparator("/"))
+		keys := []string{k1, k2, k3}
+		for i, k := range keys {
+			tr.Insert(k, fmt.Sprintf("v%d", i))
+		}
+
+		var buf bytes.Buffer
+		if err := tr.MarshalBinary(&buf, encodeStringValue); err != nil {
+			t.Fatalf("MarshalBinary failed: %v", err)
+		}
+		restored, err := trie.UnmarshalBinary(&buf, decodeStringValue)
+		if err != nil {
+			t.Fatalf("UnmarshalBinary failed: %v", err)
+		}
+
+		for _, k := range keys {
+			if !matchSetsEqual(tr.Match(k), restored.Match(k)) {
+				t.Fatalf("Match(%q) differs after round-trip", k)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package trie
 
 import (
 	"strings"
+	"sync"
 )
 
 type Trie struct {
@@ -11,11 +12,28 @@ type Trie struct {
 	separator  string
 	isSWild    bool
 	isMWild    bool
+	isCompact  bool
+	// mu guards root against concurrent Txn commits and guards watches. It
+	// is shared (by pointer) between a trie and any Snapshots taken from
+	// it, so a Watch registered on a snapshot still observes commits made
+	// against the live trie it was taken from.
+	mu *sync.Mutex
+	// watches holds, per watched prefix, the channels waiting to be closed
+	// on the next commit that writes at or beneath that prefix. See Watch.
+	// Like mu, it is shared with Snapshots.
+	watches map[string][]chan struct{}
 }
 
 type Node struct {
 	children map[string]*Node
 	value    interface{}
+	// prefix holds the key parts shared since this node's parent. It is only
+	// populated in compact (radix/PATRICIA) mode, where a run of parts with a
+	// single child each is collapsed onto one node instead of one node per
+	// part. The node is keyed in its parent's children map by prefix[0].
+	// Wildcard parts are never folded into a multi-part prefix, so a node
+	// whose prefix represents a wildcard always has len(prefix) == 1.
+	prefix []string
 }
 
 type Option func(*Trie)
@@ -59,10 +77,25 @@ func WithSeparator(sep string) Option {
 	}
 }
 
+// WithCompact switches the trie to a radix/PATRICIA-style storage mode: runs
+// of key parts that have only one child each are stored as a single node's
+// prefix instead of one node per part. This trades a little CPU on Insert
+// (for splitting/merging prefixes) for a much smaller node count, which
+// matters on TinyGo targets with long, sparsely-branching key hierarchies
+// (e.g. MQTT topics). Wildcard parts are always kept on their own node so
+// existing `+`/`#` semantics are unaffected.
+func WithCompact() Option {
+	return func(t *Trie) {
+		t.isCompact = true
+	}
+}
+
 func New(options ...Option) *Trie {
 	t := &Trie{
 		root:      &Node{children: make(map[string]*Node)},
 		separator: "", // Default separator is an empty string
+		mu:        &sync.Mutex{},
+		watches:   make(map[string][]chan struct{}),
 	}
 	for _, opt := range options {
 		opt(t)
@@ -71,6 +104,10 @@ func New(options ...Option) *Trie {
 }
 
 func (t *Trie) Insert(key string, value interface{}) (bool, error) {
+	if t.isCompact {
+		return t.insertCompact(key, value)
+	}
+
 	node := t.root
 	parts := strings.Split(key, t.separator)
 	for i, part := range parts {
@@ -87,6 +124,10 @@ func (t *Trie) Insert(key string, value interface{}) (bool, error) {
 }
 
 func (t *Trie) Retrieve(key string) (interface{}, error) {
+	if t.isCompact {
+		return t.retrieveCompact(key)
+	}
+
 	node := t.root
 	parts := strings.Split(key, t.separator)
 	for i, part := range parts {
@@ -111,12 +152,20 @@ func collectAll(startNode *Node, startKeypart string, matches *[]KeyValue, separ
 			*matches = append(*matches, KeyValue{Key: current.keypart, Value: current.node.value})
 		}
 		for k, v := range current.node.children {
-			stack = append(stack, stackNode{node: v, keypart: current.keypart + k + separator})
+			childKeypart := k
+			if current.keypart != "" {
+				childKeypart = current.keypart + separator + k
+			}
+			stack = append(stack, stackNode{node: v, keypart: childKeypart})
 		}
 	}
 }
 
 func (t *Trie) Match(key string) []KeyValue {
+	if t.isCompact {
+		return t.matchCompact(key)
+	}
+
 	var matches []KeyValue
 
 	parts := strings.Split(key, t.separator)
@@ -128,7 +177,11 @@ func (t *Trie) Match(key string) []KeyValue {
 		node, i, keypart := current.node, current.i, current.keypart
 
 		if t.isMWild && parts[i] == t.multiWild {
-			collectAll(node, keypart, &matches, t.separator)
+			ownKey := keypart
+			if t.separator != "" {
+				ownKey = strings.TrimSuffix(keypart, t.separator)
+			}
+			collectAll(node, ownKey, &matches, t.separator)
 		} else if t.isSWild && parts[i] == t.singleWild {
 			for k, childNode := range node.children {
 				if i == len(parts)-1 && childNode.value != nil {
@@ -171,6 +224,10 @@ func (t *Trie) Match(key string) []KeyValue {
 }
 
 func (t *Trie) Delete(key string) bool {
+	if t.isCompact {
+		return t.deleteCompact(key)
+	}
+
 	parentStack := []*Node{t.root}
 	node := t.root
 	parts := strings.Split(key, t.separator)
@@ -197,3 +254,424 @@ func (t *Trie) Delete(key string) bool {
 	}
 	return true
 }
+
+// isWildPart reports whether part is a wildcard under t's configuration.
+func (t *Trie) isWildPart(part string) bool {
+	return (t.isSWild && part == t.singleWild) || (t.isMWild && part == t.multiWild)
+}
+
+// compactRun returns the run of parts, starting at start, that should be
+// folded onto a single new node's prefix: a wildcard part always forms a
+// run of its own, otherwise the run extends up to (but not including) the
+// next wildcard part.
+func (t *Trie) compactRun(parts []string, start int) []string {
+	if t.isWildPart(parts[start]) {
+		return append([]string(nil), parts[start])
+	}
+	end := start + 1
+	for end < len(parts) && !t.isWildPart(parts[end]) {
+		end++
+	}
+	return append([]string(nil), parts[start:end]...)
+}
+
+func (t *Trie) insertCompact(key string, value interface{}) (bool, error) {
+	parts := strings.Split(key, t.separator)
+	for i, part := range parts {
+		if t.isMWild && part == t.multiWild && i != len(parts)-1 {
+			return false, trieError{message: "error: multi-level wildcard '" + t.multiWild + "' permitted only at the end of the insert key."}
+		}
+	}
+
+	node := t.root
+	i := 0
+	for {
+		if i >= len(parts) {
+			node.value = value
+			return true, nil
+		}
+
+		part := parts[i]
+		child, exists := node.children[part]
+		if !exists {
+			run := t.compactRun(parts, i)
+			newNode := &Node{children: make(map[string]*Node), prefix: run}
+			node.children[part] = newNode
+			i += len(run)
+			node = newNode
+			continue
+		}
+
+		common := 0
+		for common < len(child.prefix) && i+common < len(parts) && child.prefix[common] == parts[i+common] {
+			common++
+		}
+
+		if common == len(child.prefix) {
+			i += common
+			node = child
+			continue
+		}
+
+		// The new key diverges partway through child's prefix: split child
+		// into a shared node and a shortened remainder, preserving child's
+		// own subtree beneath the remainder.
+		split := &Node{children: make(map[string]*Node), prefix: append([]string(nil), child.prefix[:common]...)}
+		child.prefix = child.prefix[common:]
+		split.children[child.prefix[0]] = child
+		node.children[part] = split
+
+		i += common
+		node = split
+	}
+}
+
+func (t *Trie) retrieveCompact(key string) (interface{}, error) {
+	node := t.root
+	parts := strings.Split(key, t.separator)
+	i := 0
+	for i < len(parts) {
+		if t.isMWild && parts[i] == t.multiWild && i != len(parts)-1 {
+			return nil, trieError{message: "error: multi-level wildcard '" + t.multiWild + "' permitted only at the end of the retrieve key."}
+		}
+		child, exists := node.children[parts[i]]
+		if !exists || !compactPrefixMatches(child.prefix, parts, i) {
+			return nil, nil
+		}
+		i += len(child.prefix)
+		node = child
+	}
+	return node.value, nil
+}
+
+// compactPrefixMatches reports whether node's prefix matches parts[i:] part for part.
+func compactPrefixMatches(prefix []string, parts []string, i int) bool {
+	if i+len(prefix) > len(parts) {
+		return false
+	}
+	for j, p := range prefix {
+		if parts[i+j] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func collectAllCompact(startNode *Node, startKeypart string, matches *[]KeyValue, separator string) {
+	stack := []compactStackNode{{node: startNode, keypart: startKeypart}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current.node.value != nil {
+			*matches = append(*matches, KeyValue{Key: current.keypart, Value: current.node.value})
+		}
+		for _, child := range current.node.children {
+			childKeypart := strings.Join(child.prefix, separator)
+			if current.keypart != "" {
+				childKeypart = current.keypart + separator + childKeypart
+			}
+			stack = append(stack, compactStackNode{node: child, keypart: childKeypart})
+		}
+	}
+}
+
+type compactStackNode struct {
+	node    *Node
+	keypart string
+}
+
+// compactFrame walks the trie one key-part at a time, even inside a single
+// node's multi-part prefix, so that a wildcard part lines up against exactly
+// one stored part rather than swallowing a whole compacted run. parent is
+// the keypart through the node's own parent, with a trailing separator
+// already applied (or empty); pos indexes into node.prefix.
+type compactFrame struct {
+	node   *Node
+	parent string
+	pos    int
+	i      int
+}
+
+func (t *Trie) matchCompact(key string) []KeyValue {
+	var matches []KeyValue
+	parts := strings.Split(key, t.separator)
+	stack := []compactFrame{{node: t.root}}
+
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if f.pos < len(f.node.prefix) {
+			if f.i >= len(parts) {
+				continue
+			}
+			if t.isMWild && parts[f.i] == t.multiWild {
+				full := f.parent + strings.Join(f.node.prefix, t.separator)
+				collectAllCompact(f.node, full, &matches, t.separator)
+				continue
+			}
+			storedPart := f.node.prefix[f.pos]
+			accept := storedPart == parts[f.i]
+			if t.isSWild && (parts[f.i] == t.singleWild || storedPart == t.singleWild) {
+				accept = true
+			}
+			if accept {
+				stack = append(stack, compactFrame{node: f.node, parent: f.parent, pos: f.pos + 1, i: f.i + 1})
+			}
+			continue
+		}
+
+		nodeKey := f.parent + strings.Join(f.node.prefix, t.separator)
+
+		if f.i == len(parts) {
+			if f.node.value != nil {
+				matches = append(matches, KeyValue{Key: nodeKey, Value: f.node.value})
+			}
+			continue
+		}
+
+		childParent := nodeKey
+		if childParent != "" {
+			childParent += t.separator
+		}
+
+		if t.isMWild && parts[f.i] == t.multiWild {
+			collectAllCompact(f.node, nodeKey, &matches, t.separator)
+			continue
+		}
+
+		if t.isSWild && parts[f.i] == t.singleWild {
+			for _, child := range f.node.children {
+				stack = append(stack, compactFrame{node: child, parent: childParent, pos: 0, i: f.i})
+			}
+			continue
+		}
+
+		if child, exists := f.node.children[parts[f.i]]; exists {
+			stack = append(stack, compactFrame{node: child, parent: childParent, pos: 0, i: f.i})
+		}
+
+		if t.isSWild {
+			if wildChild, exists := f.node.children[t.singleWild]; exists {
+				stack = append(stack, compactFrame{node: wildChild, parent: childParent, pos: 0, i: f.i})
+			}
+		}
+
+		if t.isMWild {
+			if mwChild, exists := f.node.children[t.multiWild]; exists && mwChild.value != nil {
+				matches = append(matches, KeyValue{Key: childParent + t.multiWild, Value: mwChild.value})
+			}
+		}
+	}
+	return matches
+}
+
+func (t *Trie) deleteCompact(key string) bool {
+	type frame struct {
+		parent *Node
+		key    string
+	}
+
+	parts := strings.Split(key, t.separator)
+	var stack []frame
+	node := t.root
+	i := 0
+	for i < len(parts) {
+		part := parts[i]
+		child, exists := node.children[part]
+		if !exists || !compactPrefixMatches(child.prefix, parts, i) {
+			return false
+		}
+		stack = append(stack, frame{parent: node, key: part})
+		i += len(child.prefix)
+		node = child
+	}
+	if node.value == nil {
+		return false
+	}
+	node.value = nil
+
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.value != nil {
+			break
+		}
+		switch len(node.children) {
+		case 0:
+			delete(top.parent.children, top.key)
+			node = top.parent
+		case 1:
+			var onlyChild *Node
+			for _, v := range node.children {
+				onlyChild = v
+			}
+			if t.isWildPart(node.prefix[0]) || t.isWildPart(onlyChild.prefix[0]) {
+				return true
+			}
+			onlyChild.prefix = append(append([]string(nil), node.prefix...), onlyChild.prefix...)
+			top.parent.children[top.key] = onlyChild
+			return true
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// LongestPrefixMatch returns the value stored at the deepest stored key
+// that is a literal prefix of key (i.e. key's parts, taken in order, start
+// with that stored key's parts), along with the matched key itself. It
+// does not treat singleWild/multiWild specially: a stored key containing a
+// wildcard part only matches an incoming key with that exact literal part,
+// complementing Match's wildcard-aware lookup.
+func (t *Trie) LongestPrefixMatch(key string) (string, interface{}, bool) {
+	if t.isCompact {
+		return t.longestPrefixMatchCompact(key)
+	}
+
+	parts := strings.Split(key, t.separator)
+	node := t.root
+	lastIdx := -1
+	var lastValue interface{}
+	for i, part := range parts {
+		child, exists := node.children[part]
+		if !exists {
+			break
+		}
+		node = child
+		if node.value != nil {
+			lastIdx = i
+			lastValue = node.value
+		}
+	}
+	if lastIdx < 0 {
+		return "", nil, false
+	}
+	return strings.Join(parts[:lastIdx+1], t.separator), lastValue, true
+}
+
+func (t *Trie) longestPrefixMatchCompact(key string) (string, interface{}, bool) {
+	parts := strings.Split(key, t.separator)
+	node := t.root
+	consumed := 0
+	lastIdx := -1
+	var lastValue interface{}
+	for consumed < len(parts) {
+		child, exists := node.children[parts[consumed]]
+		if !exists || !compactPrefixMatches(child.prefix, parts, consumed) {
+			break
+		}
+		consumed += len(child.prefix)
+		node = child
+		if node.value != nil {
+			lastIdx = consumed - 1
+			lastValue = node.value
+		}
+	}
+	if lastIdx < 0 {
+		return "", nil, false
+	}
+	return strings.Join(parts[:lastIdx+1], t.separator), lastValue, true
+}
+
+// WalkPrefix navigates to the node identified by the literal key prefix
+// and invokes fn for every stored value at or beneath it, depth-first. It
+// stops early if fn returns false. Like LongestPrefixMatch, prefix is
+// matched literally; singleWild/multiWild parts are not treated specially.
+func (t *Trie) WalkPrefix(prefix string, fn func(key string, value interface{}) bool) {
+	if t.isCompact {
+		t.walkPrefixCompact(prefix, fn)
+		return
+	}
+
+	parts := strings.Split(prefix, t.separator)
+	node := t.root
+	for _, part := range parts {
+		child, exists := node.children[part]
+		if !exists {
+			return
+		}
+		node = child
+	}
+	walkNode(node, append([]string(nil), parts...), fn, t.separator)
+}
+
+func walkNode(node *Node, keyParts []string, fn func(string, interface{}) bool, separator string) bool {
+	if node.value != nil {
+		if !fn(strings.Join(keyParts, separator), node.value) {
+			return false
+		}
+	}
+	for part, child := range node.children {
+		childParts := make([]string, len(keyParts)+1)
+		copy(childParts, keyParts)
+		childParts[len(keyParts)] = part
+		if !walkNode(child, childParts, fn, separator) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Trie) walkPrefixCompact(prefix string, fn func(key string, value interface{}) bool) {
+	parts := strings.Split(prefix, t.separator)
+	node := t.root
+	pos, i := 0, 0
+	for i < len(parts) {
+		if pos == len(node.prefix) {
+			child, exists := node.children[parts[i]]
+			if !exists {
+				return
+			}
+			node = child
+			pos = 0
+			continue
+		}
+		if node.prefix[pos] != parts[i] {
+			return
+		}
+		pos++
+		i++
+	}
+	walkNodeCompact(node, pos, append([]string(nil), parts...), fn, t.separator)
+}
+
+// walkNodeCompact walks node and its descendants depth-first. pos is how
+// far into node's own prefix the caller has already matched (0 unless the
+// walk's starting prefix ended partway through a compacted run); keyParts
+// is the key path up to, but not including, node.prefix[pos:].
+func walkNodeCompact(node *Node, pos int, keyParts []string, fn func(string, interface{}) bool, separator string) bool {
+	full := make([]string, len(keyParts)+len(node.prefix)-pos)
+	copy(full, keyParts)
+	copy(full[len(keyParts):], node.prefix[pos:])
+
+	if node.value != nil {
+		if !fn(strings.Join(full, separator), node.value) {
+			return false
+		}
+	}
+	for _, child := range node.children {
+		if !walkNodeCompact(child, 0, full, fn, separator) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns every literal key with a stored value, in no particular
+// order.
+func (t *Trie) Keys() []string {
+	var keys []string
+	collect := func(k string, v interface{}) bool {
+		keys = append(keys, k)
+		return true
+	}
+	if t.isCompact {
+		walkNodeCompact(t.root, 0, nil, collect, t.separator)
+	} else {
+		walkNode(t.root, nil, collect, t.separator)
+	}
+	return keys
+}
@@ -1,6 +1,7 @@
 package trie_test
 
 import (
+	"sort"
 	"strings"
 	"testing"
 
@@ -273,3 +274,220 @@ func TestCustomSeparatorEdgeCases(t *testing.T) {
 		t.Fatal("Incorrect number of matches with custom separator and wildcards")
 	}
 }
+
+func TestCompactInsertionAndRetrieval(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSeparator("/"))
+
+	tr.Insert("a/b/c/d", "value1")
+	tr.Insert("a/b/c/f", "value2")
+
+	if val, _ := tr.Retrieve("a/b/c/d"); val != "value1" {
+		t.Fatal("Failed to retrieve value for key in compact mode")
+	}
+	if val, _ := tr.Retrieve("a/b/c/f"); val != "value2" {
+		t.Fatal("Failed to retrieve value for diverging key in compact mode")
+	}
+	if val, _ := tr.Retrieve("a/b/c"); val != nil {
+		t.Fatal("Retrieved value for non-existent prefix key in compact mode")
+	}
+
+	// Inserting a/b/x should split the compacted "a/b/c" run.
+	tr.Insert("a/b/x", "value3")
+	if val, _ := tr.Retrieve("a/b/x"); val != "value3" {
+		t.Fatal("Failed to retrieve value after split in compact mode")
+	}
+	if val, _ := tr.Retrieve("a/b/c/d"); val != "value1" {
+		t.Fatal("Split affected an unrelated key in compact mode")
+	}
+}
+
+func TestCompactDeletion(t *testing.T) {
+	tr := trie.New(trie.WithCompact())
+	tr.Insert("abcd", "value1")
+	tr.Insert("abcde", "value2")
+
+	if !tr.Delete("abcd") {
+		t.Fatal("Failed to delete internal node in compact mode")
+	}
+	if val, _ := tr.Retrieve("abcd"); val != nil {
+		t.Fatal("Failed to delete value of internal node in compact mode")
+	}
+	if val, _ := tr.Retrieve("abcde"); val != "value2" {
+		t.Fatal("Affected other keys while deleting internal node in compact mode")
+	}
+}
+
+func TestCompactMatchWithWildcards(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+
+	tr.Insert("a/b/c/d", "value1")
+	tr.Insert("a/b/c/f", "value2")
+	tr.Insert("a/b/d/#", "value3")
+
+	matches := tr.Match("a/b/c/d")
+	if len(matches) != 1 {
+		t.Fatal("Incorrect number of matches in compact mode")
+	}
+
+	matches = tr.Match("a/b/c/+")
+	if len(matches) != 2 {
+		t.Fatal("Incorrect number of matches for single wildcard in compact mode")
+	}
+
+	matches = tr.Match("a/b/+/#")
+	if len(matches) != 3 {
+		t.Fatal("Incorrect number of matches for overlapping wildcards in compact mode")
+	}
+}
+
+func TestCompactMatchMultiWildPreservesKeys(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+
+	tr.Insert("a/c", "value1")
+	tr.Insert("b/a/c", "value2")
+	tr.Insert("b/b", "value3")
+	tr.Insert("c/c/b", "value4")
+
+	matches := tr.Match("+/#")
+	got := make(map[string]interface{}, len(matches))
+	for _, kv := range matches {
+		got[kv.Key] = kv.Value
+	}
+
+	want := map[string]interface{}{
+		"a/c":   "value1",
+		"b/a/c": "value2",
+		"b/b":   "value3",
+		"c/c/b": "value4",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Incorrect matches, got %v want %v", got, want)
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Fatalf("Incorrect reconstructed key/value, got %v want %v", got, want)
+		}
+	}
+}
+
+func TestMatchKeysIdenticalAcrossCompactAndPlain(t *testing.T) {
+	keys := []string{"a/c", "b/a/c", "b/b", "c/c/b"}
+
+	plain := trie.New(trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+	compact := trie.New(trie.WithCompact(), trie.WithSingleWild("+"), trie.WithMultiWild("#"), trie.WithSeparator("/"))
+	for i, k := range keys {
+		plain.Insert(k, i)
+		compact.Insert(k, i)
+	}
+
+	for _, query := range []string{"a/#", "+/#", "#"} {
+		plainKeys := make(map[string]bool)
+		for _, kv := range plain.Match(query) {
+			plainKeys[kv.Key] = true
+		}
+		compactKeys := make(map[string]bool)
+		for _, kv := range compact.Match(query) {
+			compactKeys[kv.Key] = true
+		}
+		if len(plainKeys) != len(compactKeys) {
+			t.Fatalf("Match(%q): key count differs between plain %v and compact %v", query, plainKeys, compactKeys)
+		}
+		for key := range plainKeys {
+			if !compactKeys[key] {
+				t.Fatalf("Match(%q): key %q present in plain mode but not compact mode (plain=%v compact=%v)", query, key, plainKeys, compactKeys)
+			}
+		}
+	}
+}
+
+func TestLongestPrefixMatch(t *testing.T) {
+	tr := trie.New(trie.WithSeparator("/"))
+	tr.Insert("a/b", "value1")
+	tr.Insert("a/b/c", "value2")
+
+	key, val, ok := tr.LongestPrefixMatch("a/b/c/d")
+	if !ok || key != "a/b/c" || val != "value2" {
+		t.Fatalf("Incorrect longest prefix match, got key=%q val=%v ok=%v", key, val, ok)
+	}
+
+	key, val, ok = tr.LongestPrefixMatch("a/b")
+	if !ok || key != "a/b" || val != "value1" {
+		t.Fatalf("Incorrect longest prefix match for exact key, got key=%q val=%v ok=%v", key, val, ok)
+	}
+
+	if _, _, ok := tr.LongestPrefixMatch("x/y"); ok {
+		t.Fatal("Incorrectly found a longest prefix match for an unrelated key")
+	}
+}
+
+func TestLongestPrefixMatchCompact(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSeparator("/"))
+	tr.Insert("a/b", "value1")
+	tr.Insert("a/b/c", "value2")
+
+	key, val, ok := tr.LongestPrefixMatch("a/b/c/d")
+	if !ok || key != "a/b/c" || val != "value2" {
+		t.Fatalf("Incorrect longest prefix match in compact mode, got key=%q val=%v ok=%v", key, val, ok)
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+	tr := trie.New(trie.WithSeparator("/"))
+	tr.Insert("a/b/c", "value1")
+	tr.Insert("a/b/d", "value2")
+	tr.Insert("a/x", "value3")
+
+	var got []string
+	tr.WalkPrefix("a/b", func(k string, v interface{}) bool {
+		got = append(got, k)
+		return true
+	})
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "a/b/c" || got[1] != "a/b/d" {
+		t.Fatalf("Incorrect keys walked under prefix, got %v", got)
+	}
+}
+
+func TestWalkPrefixStopsEarly(t *testing.T) {
+	tr := trie.New(trie.WithSeparator("/"))
+	tr.Insert("a/b", "value1")
+	tr.Insert("a/c", "value2")
+
+	calls := 0
+	tr.WalkPrefix("a", func(k string, v interface{}) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Fatalf("WalkPrefix did not stop after fn returned false, got %d calls", calls)
+	}
+}
+
+func TestWalkPrefixCompactMidRun(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSeparator("/"))
+	tr.Insert("a/b/c/d", "value1")
+	tr.Insert("a/b/c/e", "value2")
+
+	var got []string
+	tr.WalkPrefix("a/b", func(k string, v interface{}) bool {
+		got = append(got, k)
+		return true
+	})
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "a/b/c/d" || got[1] != "a/b/c/e" {
+		t.Fatalf("Incorrect keys walked from a prefix ending mid-compacted-run, got %v", got)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSeparator("/"))
+	tr.Insert("a/b/c", "value1")
+	tr.Insert("a/b/d", "value2")
+	tr.Insert("x", "value3")
+
+	keys := tr.Keys()
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "a/b/c" || keys[1] != "a/b/d" || keys[2] != "x" {
+		t.Fatalf("Incorrect keys, got %v", keys)
+	}
+}
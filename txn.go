@@ -0,0 +1,329 @@
+package trie
+
+import "strings"
+
+// Txn is a transaction against a Trie. Writes made through a Txn
+// copy-on-write: only the nodes along a modified key's path are cloned, so
+// unrelated subtrees are shared between the trie's current tree and the
+// transaction's in-progress one. Nothing is visible outside the
+// transaction until Commit is called, which atomically swaps the trie's
+// root so concurrent readers (including Snapshots) never observe a
+// partially-written tree.
+type Txn struct {
+	trie *Trie
+	root *Node
+	// base is the trie's root as it was when this transaction started, so
+	// Abort can restore it without re-reading the live trie's root (which
+	// a concurrent Commit may have already advanced).
+	base   *Node
+	writes []string
+}
+
+// Txn starts a new transaction against t's current tree.
+func (t *Trie) Txn() *Txn {
+	t.mu.Lock()
+	root := t.root
+	t.mu.Unlock()
+	return &Txn{trie: t, root: root, base: root}
+}
+
+// Snapshot returns an immutable, O(1) view of the trie as it exists right
+// now. Reads against the returned *Trie (Retrieve, Match, ...) observe
+// exactly this point in time and are unaffected by later Txn commits made
+// against the original trie. The guarantee only holds for writers that use
+// Txn: the legacy in-place Insert/Delete still mutate nodes directly and
+// will be visible through outstanding snapshots.
+func (t *Trie) Snapshot() *Trie {
+	t.mu.Lock()
+	root := t.root
+	t.mu.Unlock()
+	return &Trie{
+		root:       root,
+		singleWild: t.singleWild,
+		multiWild:  t.multiWild,
+		separator:  t.separator,
+		isSWild:    t.isSWild,
+		isMWild:    t.isMWild,
+		isCompact:  t.isCompact,
+		mu:         t.mu,
+		watches:    t.watches,
+	}
+}
+
+// Watch returns a channel that is closed the next time a transaction is
+// committed against the trie with a write at or beneath prefix. Watches are
+// shared between a trie and its Snapshots, so calling Watch on either one
+// registers the same watch; commits made before Watch is called do not
+// close the returned channel.
+func (t *Trie) Watch(prefix string) <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch := make(chan struct{})
+	t.watches[prefix] = append(t.watches[prefix], ch)
+	return ch
+}
+
+// notifyWatches closes and removes every registered watch whose prefix lies
+// at or above one of writtenKeys. Callers must hold t.mu.
+func (t *Trie) notifyWatches(writtenKeys []string) {
+	if len(t.watches) == 0 || len(writtenKeys) == 0 {
+		return
+	}
+	for prefix, chans := range t.watches {
+		if !t.touchesPrefix(prefix, writtenKeys) {
+			continue
+		}
+		for _, ch := range chans {
+			close(ch)
+		}
+		delete(t.watches, prefix)
+	}
+}
+
+// touchesPrefix reports whether any of keys was written at or beneath
+// prefix (i.e. prefix's parts are a leading sub-slice of the key's parts).
+func (t *Trie) touchesPrefix(prefix string, keys []string) bool {
+	prefixParts := strings.Split(prefix, t.separator)
+	for _, key := range keys {
+		keyParts := strings.Split(key, t.separator)
+		if len(keyParts) < len(prefixParts) {
+			continue
+		}
+		match := true
+		for i, part := range prefixParts {
+			if keyParts[i] != part {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit publishes the transaction's tree as the trie's new root. The swap
+// is atomic: concurrent readers see either the pre-transaction tree or the
+// fully-built post-transaction tree, never one in between.
+func (txn *Txn) Commit() {
+	t := txn.trie
+	t.mu.Lock()
+	t.root = txn.root
+	t.notifyWatches(txn.writes)
+	t.mu.Unlock()
+}
+
+// Abort discards the transaction's pending writes. The trie is left
+// exactly as it was before the transaction started.
+func (txn *Txn) Abort() {
+	txn.root = txn.base
+	txn.writes = nil
+}
+
+// cloneNode makes a shallow copy of n: its children map and prefix slice
+// are copied (so the clone can be mutated independently), but the child
+// *Node pointers themselves are shared until a write descends into them.
+func cloneNode(n *Node) *Node {
+	children := make(map[string]*Node, len(n.children))
+	for k, v := range n.children {
+		children[k] = v
+	}
+	var prefix []string
+	if n.prefix != nil {
+		prefix = append([]string(nil), n.prefix...)
+	}
+	return &Node{children: children, value: n.value, prefix: prefix}
+}
+
+// Insert behaves like (*Trie).Insert, but only clones the nodes along
+// key's path rather than mutating the tree the transaction started from.
+func (txn *Txn) Insert(key string, value interface{}) (bool, error) {
+	t := txn.trie
+	if t.isCompact {
+		return txn.insertCompact(key, value)
+	}
+
+	parts := strings.Split(key, t.separator)
+	newRoot := cloneNode(txn.root)
+	node := newRoot
+	for i, part := range parts {
+		if t.isMWild && part == t.multiWild && i != len(parts)-1 {
+			return false, trieError{message: "error: multi-level wildcard '" + t.multiWild + "' permitted only at the end of the insert key."}
+		}
+		var next *Node
+		if child, exists := node.children[part]; exists {
+			next = cloneNode(child)
+		} else {
+			next = &Node{children: make(map[string]*Node)}
+		}
+		node.children[part] = next
+		node = next
+	}
+	node.value = value
+	txn.root = newRoot
+	txn.writes = append(txn.writes, key)
+	return true, nil
+}
+
+// Delete behaves like (*Trie).Delete, but only clones the nodes along
+// key's path rather than mutating the tree the transaction started from.
+func (txn *Txn) Delete(key string) bool {
+	t := txn.trie
+	if t.isCompact {
+		return txn.deleteCompact(key)
+	}
+
+	parts := strings.Split(key, t.separator)
+	newRoot := cloneNode(txn.root)
+	parentStack := []*Node{newRoot}
+	node := newRoot
+	for _, part := range parts {
+		child, exists := node.children[part]
+		if !exists {
+			return false
+		}
+		clone := cloneNode(child)
+		node.children[part] = clone
+		parentStack = append(parentStack, clone)
+		node = clone
+	}
+	if node.value == nil {
+		return false
+	}
+	node.value = nil
+	for i := len(parts) - 1; i >= 0; i-- {
+		if node.value != nil || len(node.children) > 0 {
+			break
+		}
+		parentStack = parentStack[:len(parentStack)-1]
+		parent := parentStack[len(parentStack)-1]
+		delete(parent.children, parts[i])
+		node = parent
+	}
+	txn.root = newRoot
+	txn.writes = append(txn.writes, key)
+	return true
+}
+
+func (txn *Txn) insertCompact(key string, value interface{}) (bool, error) {
+	t := txn.trie
+	parts := strings.Split(key, t.separator)
+	for i, part := range parts {
+		if t.isMWild && part == t.multiWild && i != len(parts)-1 {
+			return false, trieError{message: "error: multi-level wildcard '" + t.multiWild + "' permitted only at the end of the insert key."}
+		}
+	}
+
+	newRoot := cloneNode(txn.root)
+	node := newRoot
+	i := 0
+	for {
+		if i >= len(parts) {
+			node.value = value
+			txn.root = newRoot
+			txn.writes = append(txn.writes, key)
+			return true, nil
+		}
+
+		part := parts[i]
+		child, exists := node.children[part]
+		if !exists {
+			run := t.compactRun(parts, i)
+			newNode := &Node{children: make(map[string]*Node), prefix: run}
+			node.children[part] = newNode
+			i += len(run)
+			node = newNode
+			continue
+		}
+
+		clone := cloneNode(child)
+		node.children[part] = clone
+
+		common := 0
+		for common < len(clone.prefix) && i+common < len(parts) && clone.prefix[common] == parts[i+common] {
+			common++
+		}
+
+		if common == len(clone.prefix) {
+			i += common
+			node = clone
+			continue
+		}
+
+		split := &Node{children: make(map[string]*Node), prefix: append([]string(nil), clone.prefix[:common]...)}
+		clone.prefix = clone.prefix[common:]
+		split.children[clone.prefix[0]] = clone
+		node.children[part] = split
+
+		i += common
+		node = split
+	}
+}
+
+func (txn *Txn) deleteCompact(key string) bool {
+	type frame struct {
+		parent *Node
+		key    string
+	}
+
+	t := txn.trie
+	parts := strings.Split(key, t.separator)
+	newRoot := cloneNode(txn.root)
+	var stack []frame
+	node := newRoot
+	i := 0
+	for i < len(parts) {
+		part := parts[i]
+		child, exists := node.children[part]
+		if !exists || !compactPrefixMatches(child.prefix, parts, i) {
+			return false
+		}
+		clone := cloneNode(child)
+		node.children[part] = clone
+		stack = append(stack, frame{parent: node, key: part})
+		i += len(clone.prefix)
+		node = clone
+	}
+	if node.value == nil {
+		return false
+	}
+	node.value = nil
+
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.value != nil {
+			break
+		}
+		switch len(node.children) {
+		case 0:
+			delete(top.parent.children, top.key)
+			node = top.parent
+		case 1:
+			var onlyChild *Node
+			for _, v := range node.children {
+				onlyChild = v
+			}
+			if t.isWildPart(node.prefix[0]) || t.isWildPart(onlyChild.prefix[0]) {
+				txn.root = newRoot
+				txn.writes = append(txn.writes, key)
+				return true
+			}
+			merged := cloneNode(onlyChild)
+			merged.prefix = append(append([]string(nil), node.prefix...), merged.prefix...)
+			top.parent.children[top.key] = merged
+			txn.root = newRoot
+			txn.writes = append(txn.writes, key)
+			return true
+		default:
+			txn.root = newRoot
+			txn.writes = append(txn.writes, key)
+			return true
+		}
+	}
+	txn.root = newRoot
+	txn.writes = append(txn.writes, key)
+	return true
+}
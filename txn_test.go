@@ -0,0 +1,130 @@
+package trie_test
+
+import (
+	"sync"
+	"testing"
+
+	trie "github.com/jangala-dev/tinygo-trie"
+)
+
+func TestTxnCommitIsolatesSnapshot(t *testing.T) {
+	tr := trie.New()
+	tr.Insert("a", "orig")
+
+	snap := tr.Snapshot()
+
+	txn := tr.Txn()
+	txn.Insert("a", "new")
+	txn.Insert("b", "b-val")
+	txn.Commit()
+
+	if val, _ := snap.Retrieve("a"); val != "orig" {
+		t.Fatal("snapshot observed a write made after it was taken")
+	}
+	if val, _ := tr.Retrieve("a"); val != "new" {
+		t.Fatal("committed write not visible on the trie")
+	}
+	if val, _ := tr.Retrieve("b"); val != "b-val" {
+		t.Fatal("committed insert of a new key not visible on the trie")
+	}
+}
+
+func TestTxnAbortDiscardsWrites(t *testing.T) {
+	tr := trie.New()
+	tr.Insert("a", "orig")
+
+	txn := tr.Txn()
+	txn.Insert("a", "new")
+	txn.Abort()
+	txn.Commit()
+
+	if val, _ := tr.Retrieve("a"); val != "orig" {
+		t.Fatal("abort did not discard the transaction's writes")
+	}
+}
+
+func TestTxnAbortConcurrentWithCommit(t *testing.T) {
+	tr := trie.New()
+	tr.Insert("a", "orig")
+
+	abortingTxn := tr.Txn()
+	abortingTxn.Insert("a", "should-not-land")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		other := tr.Txn()
+		other.Insert("b", "b-val")
+		other.Commit()
+	}()
+	go func() {
+		defer wg.Done()
+		abortingTxn.Abort()
+	}()
+	wg.Wait()
+}
+
+func TestTxnInCompactMode(t *testing.T) {
+	tr := trie.New(trie.WithCompact(), trie.WithSeparator("/"))
+	tr.Insert("a/b/c/d", "value1")
+
+	txn := tr.Txn()
+	if ok, _ := txn.Insert("a/b/x", "value2"); !ok {
+		t.Fatal("failed to insert via txn in compact mode")
+	}
+	if !txn.Delete("a/b/c/d") {
+		t.Fatal("failed to delete via txn in compact mode")
+	}
+	txn.Commit()
+
+	if val, _ := tr.Retrieve("a/b/x"); val != "value2" {
+		t.Fatal("committed compact-mode insert not visible")
+	}
+	if val, _ := tr.Retrieve("a/b/c/d"); val != nil {
+		t.Fatal("committed compact-mode delete not visible")
+	}
+}
+
+func TestWatchClosesOnCommitBeneathPrefix(t *testing.T) {
+	tr := trie.New(trie.WithSeparator("/"))
+	tr.Insert("a/b", "1")
+
+	snap := tr.Snapshot()
+	ch := snap.Watch("a")
+
+	select {
+	case <-ch:
+		t.Fatal("watch fired before any commit")
+	default:
+	}
+
+	txn := tr.Txn()
+	txn.Insert("a/b", "2")
+	txn.Commit()
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("watch did not fire after a commit beneath its prefix")
+	}
+}
+
+func TestWatchIgnoresUnrelatedPrefix(t *testing.T) {
+	tr := trie.New(trie.WithSeparator("/"))
+	tr.Insert("a/b", "1")
+	tr.Insert("x/y", "2")
+
+	snap := tr.Snapshot()
+	ch := snap.Watch("a")
+
+	txn := tr.Txn()
+	txn.Insert("x/y", "2-new")
+	txn.Commit()
+
+	select {
+	case <-ch:
+		t.Fatal("watch fired for a commit outside its prefix")
+	default:
+	}
+}